@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kaspanet/kaspad/cmd/kaspawallet/daemon/pb"
+	"github.com/pkg/errors"
+)
+
+// autoApproveRule lets a send bypass interactive approval when both the
+// destination address and amount fall within an operator-configured bound.
+// A zero MaxAmountSompi means "any amount to this address".
+type autoApproveRule struct {
+	Address        string `json:"address"`
+	MaxAmountSompi uint64 `json:"maxAmountSompi"`
+}
+
+// spendLimitConfig bounds how much the daemon will send out over a rolling
+// day without an interactive approval, regardless of the auto-approve
+// allowlist.
+type spendLimitConfig struct {
+	DailyCapSompi uint64
+	StateFile     string
+}
+
+// pendingTransaction is a signing request that has been staged for operator
+// approval: CreateUnsignedTransactions has already run, but Sign/Broadcast
+// are held until it is approved or rejected.
+type pendingTransaction struct {
+	ID            string
+	Destination   string
+	AmountSompi   uint64
+	ChangeAddress string
+	UnsignedTxs   [][]byte
+	CreatedAt     time.Time
+	decisionC     chan approvalDecision
+}
+
+type approvalDecision struct {
+	approved bool
+	reason   string
+}
+
+// approvalManager stages signing requests behind interactive approval, auto
+// approves ones that match the allowlist, and enforces a rolling daily spend
+// cap across every HTTP call. It is the refactored home of the inline
+// /SendMoney pipeline from before --interactive existed.
+type approvalManager struct {
+	mu          sync.Mutex
+	pending     map[string]*pendingTransaction
+	autoApprove []autoApproveRule
+	spendLimit  spendLimitConfig
+	spentToday  uint64
+	spentDay    string // YYYY-MM-DD, in UTC
+	nextID      uint64
+}
+
+func newApprovalManager(autoApprove []autoApproveRule, spendLimit spendLimitConfig) (*approvalManager, error) {
+	m := &approvalManager{
+		pending:     make(map[string]*pendingTransaction),
+		autoApprove: autoApprove,
+		spendLimit:  spendLimit,
+	}
+	if err := m.loadSpendState(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+type spendState struct {
+	Day   string `json:"day"`
+	Spent uint64 `json:"spentSompi"`
+}
+
+func (m *approvalManager) loadSpendState() error {
+	if m.spendLimit.StateFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(m.spendLimit.StateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "Error reading spend-limit state file")
+	}
+	var state spendState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return errors.Wrap(err, "Error parsing spend-limit state file")
+	}
+	m.spentDay = state.Day
+	m.spentToday = state.Spent
+	return nil
+}
+
+func (m *approvalManager) saveSpendStateLocked() error {
+	if m.spendLimit.StateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(&spendState{Day: m.spentDay, Spent: m.spentToday})
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling spend-limit state")
+	}
+	return os.WriteFile(m.spendLimit.StateFile, data, 0600)
+}
+
+func (m *approvalManager) today() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// reserveSpendLocked rolls the counter over to a new day if needed and
+// returns an error if amountSompi would push today's total past the daily
+// cap. A successful reservation must eventually be matched by either a
+// broadcast (which keeps it) or a call to release (which credits it back),
+// so a rejected approval or a send that fails after staging doesn't
+// permanently eat into the cap. Callers must hold m.mu.
+func (m *approvalManager) reserveSpendLocked(amountSompi uint64) error {
+	today := m.today()
+	if m.spentDay != today {
+		m.spentDay = today
+		m.spentToday = 0
+	}
+	if m.spendLimit.DailyCapSompi > 0 && m.spentToday+amountSompi > m.spendLimit.DailyCapSompi {
+		return errors.Errorf("daily spend cap of %d sompi exceeded: already spent %d today",
+			m.spendLimit.DailyCapSompi, m.spentToday)
+	}
+	m.spentToday += amountSompi
+	return m.saveSpendStateLocked()
+}
+
+// release credits amountSompi back to today's spend counter. It is called
+// when a reservation's transaction is rejected, or never makes it to
+// Broadcast because decryption, signing, or the broadcast call itself
+// failed.
+func (m *approvalManager) release(amountSompi uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// If the day has rolled over since the reservation was made, the
+	// counter has already reset and there's nothing to credit back.
+	if m.spentDay != m.today() {
+		return
+	}
+	if amountSompi > m.spentToday {
+		m.spentToday = 0
+	} else {
+		m.spentToday -= amountSompi
+	}
+	if err := m.saveSpendStateLocked(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error persisting released spend-limit state: %s\n", err)
+	}
+}
+
+// newID returns a unique, monotonically increasing identifier for a freshly
+// staged pending transaction.
+func (m *approvalManager) newID() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	return fmt.Sprintf("ptx-%d", m.nextID)
+}
+
+func (m *approvalManager) isAutoApproved(address string, amountSompi uint64) bool {
+	for _, rule := range m.autoApprove {
+		if rule.Address != address {
+			continue
+		}
+		if rule.MaxAmountSompi == 0 || amountSompi <= rule.MaxAmountSompi {
+			return true
+		}
+	}
+	return false
+}
+
+// stage records a signing request and returns it immediately if it is
+// auto-approved or within the allowlist; otherwise it prints the request to
+// stderr and blocks the caller until an operator approves or rejects it via
+// resolve. runApprovalPipe (stdin) is the only channel that calls resolve
+// today.
+func (m *approvalManager) stage(pt *pendingTransaction) error {
+	m.mu.Lock()
+	if err := m.reserveSpendLocked(pt.AmountSompi); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	autoApproved := m.isAutoApproved(pt.Destination, pt.AmountSompi)
+	if !autoApproved {
+		pt.decisionC = make(chan approvalDecision, 1)
+		m.pending[pt.ID] = pt
+	}
+	m.mu.Unlock()
+
+	if autoApproved {
+		return nil
+	}
+
+	// Fee and inputs aren't printed here: libkaspawallet doesn't yet expose a
+	// way to decode them back out of the raw unsigned transaction bytes, and
+	// showing a fabricated zero fee or empty input list would misinform the
+	// operator this prompt exists to protect.
+	fmt.Fprintf(os.Stderr, "\nPending transaction %s:\n", pt.ID)
+	fmt.Fprintf(os.Stderr, "  to:      %s\n", pt.Destination)
+	fmt.Fprintf(os.Stderr, "  amount:  %s KAS\n", sompiToKasString(pt.AmountSompi))
+	fmt.Fprintf(os.Stderr, "  change:  %s\n", pt.ChangeAddress)
+	fmt.Fprintf(os.Stderr, "Approve with: approve %s   or reject with: reject %s\n", pt.ID, pt.ID)
+
+	// resolve already removed pt from m.pending before sending this.
+	decision := <-pt.decisionC
+
+	if !decision.approved {
+		m.release(pt.AmountSompi)
+		return errors.Errorf("transaction %s was rejected: %s", pt.ID, decision.reason)
+	}
+	return nil
+}
+
+// resolve is called by runApprovalPipe once an operator has made a decision
+// on a staged transaction. It is exported at the approvalManager level
+// (rather than folded into runApprovalPipe) so another channel - a FIFO, an
+// HTTP endpoint - could drive the same decision without duplicating this
+// logic. Looking the transaction up and removing it from m.pending happens
+// under the same lock so two concurrent resolve calls for the same id -
+// say, a mistyped line resent while the first is still being processed -
+// can't both win: the second one finds the id already gone and returns an
+// error instead of sending a second value into the size-1 decisionC, which
+// nothing would ever read and which would otherwise wedge the caller
+// (runApprovalPipe's single goroutine) forever.
+func (m *approvalManager) resolve(id string, approved bool, reason string) error {
+	m.mu.Lock()
+	pt, ok := m.pending[id]
+	if ok {
+		delete(m.pending, id)
+	}
+	m.mu.Unlock()
+	if !ok {
+		return errors.Errorf("no pending transaction with id %s", id)
+	}
+	pt.decisionC <- approvalDecision{approved: approved, reason: reason}
+	return nil
+}
+
+// runApprovalPipe reads "approve <id>" / "reject <id> [reason]" lines from
+// stdin and resolves the matching pending transaction. It is the only
+// approval channel --interactive currently wires up; resolve is exported so
+// an additional channel (a FIFO, an HTTP endpoint) could drive it later
+// without changing approvalManager.
+func runApprovalPipe(m *approvalManager) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := splitApprovalLine(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		id := fields[1]
+		var err error
+		switch fields[0] {
+		case "approve":
+			err = m.resolve(id, true, "")
+		case "reject":
+			reason := ""
+			if len(fields) > 2 {
+				reason = fields[2]
+			}
+			err = m.resolve(id, false, reason)
+		default:
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving approval: %s\n", err)
+		}
+	}
+}
+
+// splitApprovalLine tokenizes an "approve <id>" or "reject <id> [reason]"
+// line. The command and id are split on whitespace; everything after the id
+// is returned as a single third field verbatim, so a multi-word rejection
+// reason isn't truncated to its first token.
+func splitApprovalLine(line string) []string {
+	isSpace := func(r byte) bool { return r == ' ' || r == '\t' }
+
+	i := 0
+	for i < len(line) && isSpace(line[i]) {
+		i++
+	}
+	start := i
+	for i < len(line) && !isSpace(line[i]) {
+		i++
+	}
+	if start == i {
+		return nil
+	}
+	command := line[start:i]
+
+	for i < len(line) && isSpace(line[i]) {
+		i++
+	}
+	start = i
+	for i < len(line) && !isSpace(line[i]) {
+		i++
+	}
+	if start == i {
+		return []string{command}
+	}
+	id := line[start:i]
+
+	for i < len(line) && isSpace(line[i]) {
+		i++
+	}
+	if i == len(line) {
+		return []string{command, id}
+	}
+	return []string{command, id, line[i:]}
+}
+
+func sompiToKasString(sompi uint64) string {
+	const sompiPerKas = 100000000
+	return fmt.Sprintf("%d.%08d", sompi/sompiPerKas, sompi%sompiPerKas)
+}
+
+func changeAddressFromResponse(response *pb.CreateUnsignedTransactionsResponse) string {
+	if response == nil || response.ChangeAddress == "" {
+		return ""
+	}
+	return response.ChangeAddress
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func newTestApprovalManager(t *testing.T, autoApprove []autoApproveRule, dailyCapSompi uint64) *approvalManager {
+	t.Helper()
+	m, err := newApprovalManager(autoApprove, spendLimitConfig{
+		DailyCapSompi: dailyCapSompi,
+		StateFile:     filepath.Join(t.TempDir(), "spend-state.json"),
+	})
+	if err != nil {
+		t.Fatalf("newApprovalManager: %s", err)
+	}
+	return m
+}
+
+func TestStageAutoApprovesWithinAllowlist(t *testing.T) {
+	m := newTestApprovalManager(t, []autoApproveRule{{Address: "kaspa:allowed", MaxAmountSompi: 100}}, 0)
+
+	pt := &pendingTransaction{ID: m.newID(), Destination: "kaspa:allowed", AmountSompi: 100}
+	if err := m.stage(pt); err != nil {
+		t.Fatalf("expected auto-approved transaction to stage without blocking, got %s", err)
+	}
+}
+
+func TestStageBlocksAndResolvesOutsideAllowlist(t *testing.T) {
+	m := newTestApprovalManager(t, nil, 0)
+
+	pt := &pendingTransaction{ID: m.newID(), Destination: "kaspa:other", AmountSompi: 1}
+	done := make(chan error, 1)
+	go func() { done <- m.stage(pt) }()
+
+	if err := m.resolve(pt.ID, true, ""); err != nil {
+		t.Fatalf("resolve: %s", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("expected approved transaction to stage successfully, got %s", err)
+	}
+}
+
+func TestStageReturnsErrorOnRejection(t *testing.T) {
+	m := newTestApprovalManager(t, nil, 0)
+
+	pt := &pendingTransaction{ID: m.newID(), Destination: "kaspa:other", AmountSompi: 1}
+	done := make(chan error, 1)
+	go func() { done <- m.stage(pt) }()
+
+	if err := m.resolve(pt.ID, false, "too large"); err != nil {
+		t.Fatalf("resolve: %s", err)
+	}
+	if err := <-done; err == nil {
+		t.Fatal("expected stage to return an error for a rejected transaction, got nil")
+	}
+}
+
+func TestStageEnforcesDailyCap(t *testing.T) {
+	m := newTestApprovalManager(t, []autoApproveRule{{Address: "kaspa:allowed"}}, 100)
+
+	first := &pendingTransaction{ID: m.newID(), Destination: "kaspa:allowed", AmountSompi: 60}
+	if err := m.stage(first); err != nil {
+		t.Fatalf("expected first send under the cap to stage, got %s", err)
+	}
+
+	second := &pendingTransaction{ID: m.newID(), Destination: "kaspa:allowed", AmountSompi: 60}
+	if err := m.stage(second); err == nil {
+		t.Fatal("expected second send to exceed the daily cap, got nil error")
+	}
+}
+
+func TestRejectionReleasesTheSpendCapReservation(t *testing.T) {
+	m := newTestApprovalManager(t, nil, 100)
+
+	pt := &pendingTransaction{ID: m.newID(), Destination: "kaspa:other", AmountSompi: 60}
+	done := make(chan error, 1)
+	go func() { done <- m.stage(pt) }()
+	if err := m.resolve(pt.ID, false, "changed my mind"); err != nil {
+		t.Fatalf("resolve: %s", err)
+	}
+	if err := <-done; err == nil {
+		t.Fatal("expected the rejected transaction to return an error")
+	}
+
+	// If the reservation hadn't been released, this staging would exceed
+	// the 100 sompi cap (60 + 60 > 100).
+	another := &pendingTransaction{ID: m.newID(), Destination: "kaspa:other", AmountSompi: 60}
+	done2 := make(chan error, 1)
+	go func() { done2 <- m.stage(another) }()
+	if err := m.resolve(another.ID, true, ""); err != nil {
+		t.Fatalf("resolve: %s", err)
+	}
+	if err := <-done2; err != nil {
+		t.Fatalf("expected the cap to have been released after rejection, got %s", err)
+	}
+}
+
+func TestSplitApprovalLinePreservesMultiWordReason(t *testing.T) {
+	fields := splitApprovalLine("reject ptx-1 amount looks wrong, double check with ops")
+	want := []string{"reject", "ptx-1", "amount looks wrong, double check with ops"}
+	if len(fields) != len(want) {
+		t.Fatalf("expected %v, got %v", want, fields)
+	}
+	for i := range want {
+		if fields[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, fields)
+		}
+	}
+}
+
+func TestResolveRejectsASecondCallForTheSameID(t *testing.T) {
+	m := newTestApprovalManager(t, nil, 0)
+
+	pt := &pendingTransaction{ID: m.newID(), Destination: "kaspa:other", AmountSompi: 1}
+	done := make(chan error, 1)
+	go func() { done <- m.stage(pt) }()
+
+	if err := m.resolve(pt.ID, true, ""); err != nil {
+		t.Fatalf("resolve: %s", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("expected approved transaction to stage successfully, got %s", err)
+	}
+
+	// A second resolve for the same id - e.g. a duplicated or retried line on
+	// the approval pipe - must not try to send into decisionC again, which
+	// nothing would read.
+	if err := m.resolve(pt.ID, true, ""); err == nil {
+		t.Fatal("expected a second resolve for an already-resolved id to return an error")
+	}
+}
+
+func TestReleaseAfterBroadcastFailureFreesTheCap(t *testing.T) {
+	m := newTestApprovalManager(t, []autoApproveRule{{Address: "kaspa:allowed"}}, 100)
+
+	pt := &pendingTransaction{ID: m.newID(), Destination: "kaspa:allowed", AmountSompi: 100}
+	if err := m.stage(pt); err != nil {
+		t.Fatalf("expected first send to stage under the cap, got %s", err)
+	}
+
+	// Simulate handleSendMoney's deferred release after Broadcast fails.
+	m.release(pt.AmountSompi)
+
+	retry := &pendingTransaction{ID: m.newID(), Destination: "kaspa:allowed", AmountSompi: 100}
+	if err := m.stage(retry); err != nil {
+		t.Fatalf("expected the cap to be available again after release, got %s", err)
+	}
+}
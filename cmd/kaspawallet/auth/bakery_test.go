@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestBakery(t *testing.T) *Bakery {
+	t.Helper()
+	bakery, err := LoadOrCreateBakery(filepath.Join(t.TempDir(), "macaroon.key"))
+	if err != nil {
+		t.Fatalf("LoadOrCreateBakery: %s", err)
+	}
+	return bakery
+}
+
+func TestVerifyRejectsWrongMethod(t *testing.T) {
+	bakery := newTestBakery(t)
+	macaroon := bakery.Bake(Caveat{Condition: "method", Value: "Balance"})
+
+	if err := bakery.Verify(macaroon, RequestContext{Method: "Send"}); err == nil {
+		t.Fatal("expected Verify to reject a macaroon used against a different method, got nil error")
+	}
+	if err := bakery.Verify(macaroon, RequestContext{Method: "Balance"}); err != nil {
+		t.Fatalf("expected Verify to accept the authorized method, got %s", err)
+	}
+}
+
+func TestVerifyEnforcesMaxAmount(t *testing.T) {
+	bakery := newTestBakery(t)
+	macaroon := bakery.Bake(
+		Caveat{Condition: "method", Value: "Send"},
+		Caveat{Condition: "max_amount_sompi", Value: "100"},
+	)
+
+	if err := bakery.Verify(macaroon, RequestContext{Method: "Send", AmountSompi: 101, AmountKnown: true}); err == nil {
+		t.Fatal("expected Verify to reject an amount over max_amount_sompi, got nil error")
+	}
+	if err := bakery.Verify(macaroon, RequestContext{Method: "Send", AmountSompi: 100, AmountKnown: true}); err != nil {
+		t.Fatalf("expected Verify to accept an amount at the cap, got %s", err)
+	}
+}
+
+func TestVerifyRejectsMaxAmountWhenRequestHasNoAmount(t *testing.T) {
+	bakery := newTestBakery(t)
+	macaroon := bakery.Bake(
+		Caveat{Condition: "method", Value: "Sweep"},
+		Caveat{Condition: "max_amount_sompi", Value: "100"},
+	)
+
+	// Sweep carries no amount field at all. Treating that the same as
+	// "amount 0" would let a max_amount_sompi-scoped macaroon reach a method
+	// it was never meant to authorize.
+	if err := bakery.Verify(macaroon, RequestContext{Method: "Sweep"}); err == nil {
+		t.Fatal("expected Verify to reject a method whose request carries no amount, got nil error")
+	}
+}
+
+func TestVerifyEnforcesAllowedAddress(t *testing.T) {
+	bakery := newTestBakery(t)
+	macaroon := bakery.Bake(
+		Caveat{Condition: "method", Value: "Send"},
+		Caveat{Condition: "allowed_address", Value: "kaspa:allowed"},
+	)
+
+	if err := bakery.Verify(macaroon, RequestContext{Method: "Send", Address: "kaspa:other", AddressKnown: true}); err == nil {
+		t.Fatal("expected Verify to reject a send to an address outside allowed_address, got nil error")
+	}
+	if err := bakery.Verify(macaroon, RequestContext{Method: "Send", Address: "kaspa:allowed", AddressKnown: true}); err != nil {
+		t.Fatalf("expected Verify to accept the allowed address, got %s", err)
+	}
+}
+
+func TestVerifyRejectsAllowedAddressWhenRequestHasNoAddress(t *testing.T) {
+	bakery := newTestBakery(t)
+	macaroon := bakery.Bake(
+		Caveat{Condition: "method", Value: "Balance"},
+		Caveat{Condition: "allowed_address", Value: "kaspa:allowed"},
+	)
+
+	if err := bakery.Verify(macaroon, RequestContext{Method: "Balance"}); err == nil {
+		t.Fatal("expected Verify to reject a method whose request carries no address, got nil error")
+	}
+}
+
+func TestVerifyEnforcesValidUntil(t *testing.T) {
+	bakery := newTestBakery(t)
+	macaroon := bakery.Bake(
+		Caveat{Condition: "method", Value: "Balance"},
+		Caveat{Condition: "valid_until", Value: time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)},
+	)
+
+	if err := bakery.Verify(macaroon, RequestContext{Method: "Balance"}); err == nil {
+		t.Fatal("expected Verify to reject an expired macaroon, got nil error")
+	}
+}
+
+func TestVerifyRejectsTamperedCaveat(t *testing.T) {
+	bakery := newTestBakery(t)
+	macaroon := bakery.Bake(
+		Caveat{Condition: "method", Value: "Send"},
+		Caveat{Condition: "max_amount_sompi", Value: "100"},
+	)
+
+	// Simulate a client rewriting the caveat to raise its own spending limit
+	// without the root key to re-sign it.
+	macaroon.Caveats[1].Value = "100000000000"
+
+	if err := bakery.Verify(macaroon, RequestContext{Method: "Send", AmountSompi: 100, AmountKnown: true}); err == nil {
+		t.Fatal("expected Verify to reject a macaroon with a tampered caveat, got nil error")
+	}
+}
+
+func TestVerifyRejectsForeignBakery(t *testing.T) {
+	minted := newTestBakery(t)
+	other := newTestBakery(t)
+	macaroon := minted.Bake(Caveat{Condition: "method", Value: "Balance"})
+
+	if err := other.Verify(macaroon, RequestContext{Method: "Balance"}); err == nil {
+		t.Fatal("expected Verify to reject a macaroon minted from a different root key, got nil error")
+	}
+}
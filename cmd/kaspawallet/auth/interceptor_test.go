@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func callInterceptor(t *testing.T, bakery *Bakery, md metadata.MD) error {
+	t.Helper()
+	ctx := context.Background()
+	if md != nil {
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	handlerCalled := false
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		handlerCalled = true
+		return nil, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/kaspawalletd.Kaspawalletd/GetBalance"}
+
+	_, err := bakery.UnaryServerInterceptor()(ctx, nil, info, handler)
+	if err == nil && !handlerCalled {
+		t.Fatal("expected the handler to run when the interceptor returns no error")
+	}
+	if err != nil && handlerCalled {
+		t.Fatal("expected the handler not to run when the interceptor returns an error")
+	}
+	return err
+}
+
+func TestUnaryServerInterceptorRejectsMissingMacaroon(t *testing.T) {
+	bakery := newTestBakery(t)
+
+	if err := callInterceptor(t, bakery, nil); err == nil {
+		t.Fatal("expected a call with no macaroon metadata to be rejected, got nil error")
+	}
+}
+
+func TestUnaryServerInterceptorRejectsWrongMethod(t *testing.T) {
+	bakery := newTestBakery(t)
+	macaroon := bakery.Bake(Caveat{Condition: "method", Value: "Send"})
+	token, err := macaroon.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize: %s", err)
+	}
+
+	md := metadata.Pairs(MetadataKey, token)
+	if err := callInterceptor(t, bakery, md); err == nil {
+		t.Fatal("expected a macaroon scoped to a different method to be rejected, got nil error")
+	}
+}
+
+func TestUnaryServerInterceptorAcceptsAuthorizedMacaroon(t *testing.T) {
+	bakery := newTestBakery(t)
+	macaroon := bakery.Bake(Caveat{Condition: "method", Value: "GetBalance"})
+	token, err := macaroon.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize: %s", err)
+	}
+
+	md := metadata.Pairs(MetadataKey, token)
+	if err := callInterceptor(t, bakery, md); err != nil {
+		t.Fatalf("expected a macaroon authorized for the called method to be accepted, got %s", err)
+	}
+}
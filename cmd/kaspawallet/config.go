@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/kaspanet/kaspad/domain/dagconfig"
+	"github.com/pkg/errors"
+)
+
+// Sub-command names dispatched on in main() and wired into parseCommandLine
+// below.
+const (
+	createSubCmd                    = "create"
+	balanceSubCmd                   = "balance"
+	sendSubCmd                      = "send"
+	createUnsignedTransactionSubCmd = "create-unsigned-transaction"
+	signSubCmd                      = "sign"
+	broadcastSubCmd                 = "broadcast"
+	parseSubCmd                     = "parse"
+	showAddressesSubCmd             = "show-addresses"
+	newAddressSubCmd                = "new-address"
+	dumpUnencryptedDataSubCmd       = "dump-unencrypted-data"
+	startDaemonSubCmd               = "start-daemon"
+	sweepSubCmd                     = "sweep"
+	bakeMacaroonSubCmd              = "bake-macaroon"
+)
+
+// daemonTimeout bounds every daemon RPC issued by the HTTP layer except
+// Broadcast, which gets its own timeout since signing may have blocked on
+// interactive approval or password entry for an unbounded time beforehand.
+const daemonTimeout = 30 * time.Second
+
+// configFlags are the network-selection flags shared by every sub-command.
+type configFlags struct {
+	Testnet bool `long:"testnet" description:"Use the test network"`
+	Devnet  bool `long:"devnet" description:"Use the development network"`
+	Simnet  bool `long:"simnet" description:"Use the simulation network"`
+}
+
+// sendConfig is the parsed command line shared by send and the HTTP
+// front-end's /SendMoney-equivalent routes: which keys file and daemon to
+// use, and which change address policy to apply.
+type sendConfig struct {
+	configFlags
+
+	KeysFile                 string   `long:"keysfile" description:"Path to the wallet keys file" required:"true"`
+	DaemonAddress            string   `long:"daemonaddress" description:"Address of the kaspawalletd gRPC daemon" default:"localhost:8082"`
+	FromAddresses            []string `long:"from" description:"Restrict the transaction's inputs to these addresses"`
+	UseExistingChangeAddress bool     `long:"use-existing-change-address" description:"Reuse a previously generated change address instead of deriving a new one"`
+}
+
+// NetParams resolves the selected network flags to the dagconfig.Params the
+// rest of the wallet (key derivation, libkaspawallet signing) needs. It lives
+// alongside the flags it reads so the two can't drift out of sync.
+func (c *sendConfig) NetParams() *dagconfig.Params {
+	switch {
+	case c.Testnet:
+		return &dagconfig.TestnetParams
+	case c.Devnet:
+		return &dagconfig.DevnetParams
+	case c.Simnet:
+		return &dagconfig.SimnetParams
+	default:
+		return &dagconfig.MainnetParams
+	}
+}
+
+// createConfig is the parsed `kaspawallet create` command line: how many
+// keys/signatures the new wallet requires and where to write it.
+type createConfig struct {
+	configFlags
+
+	KeysFile          string `long:"keysfile" description:"Path to save the new wallet's keys file to" required:"true"`
+	NumPublicKeys     uint32 `long:"num-public-keys" description:"Number of public keys for this wallet (for multisig wallets)" default:"1"`
+	MinimumSignatures uint32 `long:"min-signatures" description:"Minimum number of signatures required by this wallet (for multisig wallets)" default:"1"`
+	ECDSA             bool   `long:"ecdsa" description:"Create an ECDSA wallet, rather than the default Schnorr wallet"`
+}
+
+// balanceConfig is the parsed `kaspawallet balance` command line.
+type balanceConfig struct {
+	configFlags
+
+	KeysFile      string `long:"keysfile" description:"Path to the wallet keys file" required:"true"`
+	DaemonAddress string `long:"daemonaddress" description:"Address of the kaspawalletd gRPC daemon" default:"localhost:8082"`
+	Verbose       bool   `long:"verbose" short:"v" description:"Show the balance of each address, rather than just the total"`
+}
+
+// createUnsignedTransactionConfig is the parsed
+// `kaspawallet create-unsigned-transaction` command line: the same inputs
+// as send, minus the password, since the unsigned transaction it prints is
+// handed to sign separately.
+type createUnsignedTransactionConfig struct {
+	sendConfig
+
+	ToAddress  string `long:"to-address" description:"Address to send to" required:"true"`
+	SendAmount uint64 `long:"send-amount" description:"Amount to send, in sompi" required:"true"`
+}
+
+// signConfig is the parsed `kaspawallet sign` command line: the keys file to
+// decrypt and the unsigned transaction(s) to sign with it.
+type signConfig struct {
+	configFlags
+
+	KeysFile    string `long:"keysfile" description:"Path to the wallet keys file" required:"true"`
+	Transaction string `long:"transaction" description:"Unsigned transaction(s), as printed by create-unsigned-transaction" required:"true"`
+}
+
+// broadcastConfig is the parsed `kaspawallet broadcast` command line.
+type broadcastConfig struct {
+	configFlags
+
+	DaemonAddress string `long:"daemonaddress" description:"Address of the kaspawalletd gRPC daemon" default:"localhost:8082"`
+	Transaction   string `long:"transaction" description:"Signed transaction(s), as printed by sign" required:"true"`
+	NoWait        bool   `long:"no-wait" description:"Exit immediately after broadcasting instead of waiting for acceptance"`
+}
+
+// parseConfig is the parsed `kaspawallet parse` command line.
+type parseConfig struct {
+	configFlags
+
+	DaemonAddress string `long:"daemonaddress" description:"Address of the kaspawalletd gRPC daemon" default:"localhost:8082"`
+	Transaction   string `long:"transaction" description:"Transaction to parse, as printed by create-unsigned-transaction or sign" required:"true"`
+}
+
+// showAddressesConfig is the parsed `kaspawallet show-addresses` command
+// line.
+type showAddressesConfig struct {
+	configFlags
+
+	KeysFile string `long:"keysfile" description:"Path to the wallet keys file" required:"true"`
+}
+
+// newAddressConfig is the parsed `kaspawallet new-address` command line.
+type newAddressConfig struct {
+	configFlags
+
+	KeysFile string `long:"keysfile" description:"Path to the wallet keys file" required:"true"`
+}
+
+// dumpUnencryptedDataConfig is the parsed `kaspawallet dump-unencrypted-data`
+// command line.
+type dumpUnencryptedDataConfig struct {
+	configFlags
+
+	KeysFile string `long:"keysfile" description:"Path to the wallet keys file" required:"true"`
+}
+
+// sweepConfig is the parsed `kaspawallet sweep` command line: sweep
+// consolidates every UTXO the wallet controls into fresh change addresses,
+// so it needs the same keys-file/daemon/password inputs as send but no
+// destination address or amount.
+type sweepConfig struct {
+	sendConfig
+}
+
+// startDaemonConfig is the parsed `kaspawallet start-daemon` command line.
+type startDaemonConfig struct {
+	sendConfig
+
+	ListenAddress  string   `long:"httplisten" description:"Address for the REST front-end to listen on" default:"localhost:16117"`
+	TLSCertFile    string   `long:"tlscert" description:"Path to the daemon's TLS certificate, generated on first run if missing (default: keysfile + .crt)"`
+	TLSKeyFile     string   `long:"tlskey" description:"Path to the daemon's TLS key, generated on first run if missing (default: keysfile + .key)"`
+	AllowedOrigins []string `long:"allowed-origin" description:"Origin allowed to make cross-origin requests against the REST front-end (may be given multiple times)"`
+
+	MacaroonFile string `long:"macaroonfile" description:"Path to the daemon's macaroon root key file, minted on first run if missing (default: keysfile + .macaroon)"`
+
+	RPCServer             string `long:"rpcserver" description:"Address of the kaspad RPC server used for confirmation tracking" default:"localhost:16110"`
+	ConfirmationThreshold uint64 `long:"confirmationthreshold" description:"Number of blue score increments after acceptance before a transaction is considered confirmed" default:"10"`
+
+	Interactive    bool   `long:"interactive" description:"Require operator approval on stdin/FIFO before any signing route actually signs a transaction"`
+	AllowlistFile  string `long:"allowlist" description:"Path to a JSON file of per-address/per-amount auto-approve rules, re-read on every --interactive approval"`
+	DailyCapSompi  uint64 `long:"dailycap" description:"Maximum amount in sompi the daemon will send out over a rolling UTC day when --interactive is set"`
+	SpendStateFile string `long:"spendstatefile" description:"Path the daily spend-cap counter is persisted to, so a restart doesn't reset it"`
+}
+
+// AutoApprove loads the auto-approve allowlist from AllowlistFile. A missing
+// file is treated as an empty allowlist rather than an error, since
+// --interactive is useful on its own with every send requiring approval.
+func (c *startDaemonConfig) AutoApprove() ([]autoApproveRule, error) {
+	if c.AllowlistFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(c.AllowlistFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "Error reading --allowlist file")
+	}
+	var rules []autoApproveRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, errors.Wrap(err, "Error parsing --allowlist file")
+	}
+	return rules, nil
+}
+
+// SpendLimit builds the spendLimitConfig newApprovalManager expects out of
+// the daemon's --dailycap/--spendstatefile flags.
+func (c *startDaemonConfig) SpendLimit() spendLimitConfig {
+	return spendLimitConfig{DailyCapSompi: c.DailyCapSompi, StateFile: c.SpendStateFile}
+}
+
+// applyDefaults fills in TLSCertFile, TLSKeyFile and MacaroonFile from
+// KeysFile when the operator didn't set them explicitly, so `start-daemon
+// --keysfile=foo` is enough to run with mandatory TLS and macaroon auth
+// instead of requiring three more previously-undocumented flags just to
+// start.
+func (c *startDaemonConfig) applyDefaults() {
+	if c.TLSCertFile == "" {
+		c.TLSCertFile = c.KeysFile + ".crt"
+	}
+	if c.TLSKeyFile == "" {
+		c.TLSKeyFile = c.KeysFile + ".key"
+	}
+	if c.MacaroonFile == "" {
+		c.MacaroonFile = c.KeysFile + ".macaroon"
+	}
+}
+
+// httpConfig extracts the REST front-end settings out of startDaemonConfig.
+// GatewayKaspawalletdAddress is left unset here; main wires it to the
+// daemon's own gRPC listen address once it knows it.
+func (c *startDaemonConfig) httpConfig() *httpConfig {
+	return &httpConfig{
+		ListenAddress:  c.ListenAddress,
+		TLSCertFile:    c.TLSCertFile,
+		TLSKeyFile:     c.TLSKeyFile,
+		AllowedOrigins: c.AllowedOrigins,
+	}
+}
+
+// parseCommandLine parses os.Args into a sub-command name and its
+// corresponding config struct using go-flags' command groups, one per
+// sub-command registered in main's dispatch switch.
+func parseCommandLine() (subCmd string, config interface{}) {
+	cfg := struct {
+		Create                    createConfig                    `command:"create"`
+		Balance                   balanceConfig                   `command:"balance"`
+		Send                      sendConfig                      `command:"send"`
+		CreateUnsignedTransaction createUnsignedTransactionConfig `command:"create-unsigned-transaction"`
+		Sign                      signConfig                      `command:"sign"`
+		Broadcast                 broadcastConfig                 `command:"broadcast"`
+		Parse                     parseConfig                     `command:"parse"`
+		ShowAddresses             showAddressesConfig             `command:"show-addresses"`
+		NewAddress                newAddressConfig                `command:"new-address"`
+		DumpUnencryptedData       dumpUnencryptedDataConfig       `command:"dump-unencrypted-data"`
+		StartDaemon               startDaemonConfig               `command:"start-daemon"`
+		Sweep                     sweepConfig                     `command:"sweep"`
+		BakeMacaroon              bakeMacaroonConfig              `command:"bake-macaroon"`
+	}{}
+
+	parser := flags.NewParser(&cfg, flags.Default)
+	if _, err := parser.Parse(); err != nil {
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			os.Exit(0)
+		}
+		printErrorAndExit(err)
+	}
+
+	switch parser.Active.Name {
+	case "create":
+		return createSubCmd, &cfg.Create
+	case "balance":
+		return balanceSubCmd, &cfg.Balance
+	case "send":
+		return sendSubCmd, &cfg.Send
+	case "create-unsigned-transaction":
+		return createUnsignedTransactionSubCmd, &cfg.CreateUnsignedTransaction
+	case "sign":
+		return signSubCmd, &cfg.Sign
+	case "broadcast":
+		return broadcastSubCmd, &cfg.Broadcast
+	case "parse":
+		return parseSubCmd, &cfg.Parse
+	case "show-addresses":
+		return showAddressesSubCmd, &cfg.ShowAddresses
+	case "new-address":
+		return newAddressSubCmd, &cfg.NewAddress
+	case "dump-unencrypted-data":
+		return dumpUnencryptedDataSubCmd, &cfg.DumpUnencryptedData
+	case "start-daemon":
+		cfg.StartDaemon.applyDefaults()
+		return startDaemonSubCmd, &cfg.StartDaemon
+	case "sweep":
+		return sweepSubCmd, &cfg.Sweep
+	case "bake-macaroon":
+		return bakeMacaroonSubCmd, &cfg.BakeMacaroon
+	default:
+		printErrorAndExit(errors.Errorf("Unknown sub-command '%s'", parser.Active.Name))
+		return "", nil
+	}
+}
+
+func printErrorAndExit(err error) {
+	fmt.Fprintf(os.Stderr, "%s\n", err)
+	os.Exit(1)
+}
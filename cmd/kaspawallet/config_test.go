@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestStartDaemonConfigApplyDefaults(t *testing.T) {
+	c := &startDaemonConfig{}
+	c.KeysFile = "/home/user/keys.json"
+	c.applyDefaults()
+
+	if c.TLSCertFile != "/home/user/keys.json.crt" {
+		t.Errorf("TLSCertFile = %q, want %q", c.TLSCertFile, "/home/user/keys.json.crt")
+	}
+	if c.TLSKeyFile != "/home/user/keys.json.key" {
+		t.Errorf("TLSKeyFile = %q, want %q", c.TLSKeyFile, "/home/user/keys.json.key")
+	}
+	if c.MacaroonFile != "/home/user/keys.json.macaroon" {
+		t.Errorf("MacaroonFile = %q, want %q", c.MacaroonFile, "/home/user/keys.json.macaroon")
+	}
+}
+
+func TestStartDaemonConfigApplyDefaultsDoesNotOverrideExplicitFlags(t *testing.T) {
+	c := &startDaemonConfig{}
+	c.KeysFile = "/home/user/keys.json"
+	c.TLSCertFile = "/etc/kaspawallet/custom.crt"
+	c.applyDefaults()
+
+	if c.TLSCertFile != "/etc/kaspawallet/custom.crt" {
+		t.Errorf("applyDefaults overrode an explicitly set --tlscert: got %q", c.TLSCertFile)
+	}
+	if c.TLSKeyFile != "/home/user/keys.json.key" {
+		t.Errorf("TLSKeyFile = %q, want %q", c.TLSKeyFile, "/home/user/keys.json.key")
+	}
+}
@@ -0,0 +1,14 @@
+package pb
+
+// go:generate emits the gRPC server/client stubs, the grpc-gateway reverse
+// proxy (kaspawalletd.pb.gw.go) and an OpenAPI/Swagger document from
+// kaspawalletd.proto, so the REST surface in openapi/kaspawalletd.swagger.json
+// and the generated RegisterKaspawalletdHandlerFromEndpoint used by
+// startHttp in cmd/kaspawallet/main.go stay in lockstep with the gRPC
+// definitions instead of being maintained by hand. Until this has actually
+// been run in an environment with protoc and the grpc-gateway/openapiv2
+// plugins available, openapi/kaspawalletd.swagger.json is a hand-maintained
+// stand-in kept manually in sync with the RPCs below - see the "comment"
+// field in that file.
+//
+//go:generate protoc -I. -I$GOPATH/src -I$GOPATH/src/github.com/grpc-ecosystem/grpc-gateway/third_party/googleapis --go_out=plugins=grpc:. --grpc-gateway_out=logtostderr=true:. --openapiv2_out=logtostderr=true,allow_merge=true,merge_file_name=kaspawalletd:openapi kaspawalletd.proto
@@ -0,0 +1,228 @@
+// Package server hosts daemon-side subsystems that sit behind the wallet's
+// gRPC surface rather than being exposed as an RPC themselves. Tracker is
+// the first of these: it watches kaspad for blue-score and UTXO changes and
+// turns them into per-transaction confirmation events that the HTTP layer
+// can stream out over /Subscribe.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/kaspanet/kaspad/infrastructure/network/rpcclient"
+	"github.com/pkg/errors"
+)
+
+// subscriptionTokenTTL bounds how long a minted subscription token can be
+// resolved for. Without an expiry, tokens minted for transactions a client
+// never subscribed to (or already confirmed and moved on from) would
+// accumulate in Tracker.tokens for the life of the daemon process.
+const subscriptionTokenTTL = time.Hour
+
+// EventType is the kind of confirmation-lifecycle event a subscriber can
+// receive for a tracked transaction.
+type EventType string
+
+const (
+	// EventAccepted fires once a tracked transaction is accepted into the
+	// virtual selected parent chain's mergeset.
+	EventAccepted EventType = "accepted"
+	// EventConfirmed fires once a tracked transaction has crossed the
+	// configured confirmation threshold.
+	EventConfirmed EventType = "confirmed"
+	// EventReorged fires if a previously accepted transaction is removed
+	// from the virtual selected parent chain by a reorg.
+	EventReorged EventType = "reorged"
+)
+
+// Event describes a single confirmation-lifecycle update for one tracked
+// transaction ID.
+type Event struct {
+	Type          EventType `json:"type"`
+	TxID          string    `json:"txid"`
+	BlockHash     string    `json:"blockHash"`
+	BlueScore     uint64    `json:"blueScore"`
+	Confirmations uint64    `json:"confirmations"`
+}
+
+type subscriber struct {
+	txIDs  map[string]bool
+	eventC chan Event
+}
+
+// Tracker maintains a long-lived kaspad RPC connection and fans out
+// accepted/confirmed/reorged events to whichever HTTP subscribers have
+// registered interest in a given transaction ID.
+type Tracker struct {
+	rpcClient             *rpcclient.RPCClient
+	confirmationThreshold uint64
+
+	mu          sync.Mutex
+	subscribers map[*subscriber]bool
+	txBlueScore map[string]uint64     // txID -> blue score it was accepted at
+	tokens      map[string]tokenEntry // opaque subscription token -> txIDs + mint time
+
+	virtualBlueScore uint64
+}
+
+// tokenEntry is what a minted subscription token resolves to, plus when it
+// was minted so evictExpiredTokensLocked can age it out.
+type tokenEntry struct {
+	txIDs    []string
+	mintedAt time.Time
+}
+
+// NewTracker dials kaspad at rpcAddress and returns a Tracker ready to Run.
+func NewTracker(rpcAddress string, confirmationThreshold uint64) (*Tracker, error) {
+	rpcClient, err := rpcclient.NewRPCClient(rpcAddress)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error connecting to kaspad for event tracking")
+	}
+	return &Tracker{
+		rpcClient:             rpcClient,
+		confirmationThreshold: confirmationThreshold,
+		subscribers:           make(map[*subscriber]bool),
+		txBlueScore:           make(map[string]uint64),
+		tokens:                make(map[string]tokenEntry),
+	}, nil
+}
+
+// Run opens the NotifyVirtualSelectedParentBlueScoreChanged and
+// NotifyUTXOsChanged streams against kaspad and blocks, dispatching events
+// until ctx is cancelled.
+func (t *Tracker) Run(ctx context.Context) error {
+	if err := t.rpcClient.RegisterForVirtualSelectedParentBlueScoreChangedNotifications(
+		t.onBlueScoreChanged); err != nil {
+		return errors.Wrap(err, "Error registering for blue score notifications")
+	}
+	if err := t.rpcClient.RegisterForUTXOsChangedNotifications(nil, t.onUTXOsChanged); err != nil {
+		return errors.Wrap(err, "Error registering for UTXO change notifications")
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (t *Tracker) onBlueScoreChanged(blueScore uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.virtualBlueScore = blueScore
+	for txID, acceptedAt := range t.txBlueScore {
+		confirmations := blueScore - acceptedAt
+		if confirmations >= t.confirmationThreshold {
+			t.publishLocked(Event{
+				Type:          EventConfirmed,
+				TxID:          txID,
+				BlueScore:     blueScore,
+				Confirmations: confirmations,
+			})
+			delete(t.txBlueScore, txID)
+		}
+	}
+}
+
+func (t *Tracker) onUTXOsChanged(txID string, blockHash string, accepted bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !accepted {
+		if _, wasTracked := t.txBlueScore[txID]; wasTracked {
+			delete(t.txBlueScore, txID)
+			t.publishLocked(Event{Type: EventReorged, TxID: txID, BlockHash: blockHash})
+		}
+		return
+	}
+
+	t.txBlueScore[txID] = t.virtualBlueScore
+	t.publishLocked(Event{
+		Type:      EventAccepted,
+		TxID:      txID,
+		BlockHash: blockHash,
+		BlueScore: t.virtualBlueScore,
+	})
+}
+
+// publishLocked sends ev to every subscriber tracking its TxID. Callers must
+// hold t.mu.
+func (t *Tracker) publishLocked(ev Event) {
+	for sub := range t.subscribers {
+		if !sub.txIDs[ev.TxID] {
+			continue
+		}
+		select {
+		case sub.eventC <- ev:
+		default:
+			// A slow subscriber doesn't get to back-pressure the whole
+			// tracker; it simply misses events until it catches up.
+		}
+	}
+}
+
+// Subscribe registers interest in the given transaction IDs and returns a
+// channel of events plus an unsubscribe function the HTTP handler must call
+// once the client disconnects.
+func (t *Tracker) Subscribe(txIDs []string) (<-chan Event, func()) {
+	sub := &subscriber{txIDs: make(map[string]bool, len(txIDs)), eventC: make(chan Event, 32)}
+	for _, txID := range txIDs {
+		sub.txIDs[txID] = true
+	}
+
+	t.mu.Lock()
+	t.subscribers[sub] = true
+	t.mu.Unlock()
+
+	unsubscribe := func() {
+		t.mu.Lock()
+		delete(t.subscribers, sub)
+		t.mu.Unlock()
+		close(sub.eventC)
+	}
+	return sub.eventC, unsubscribe
+}
+
+// NewSubscriptionToken mints an opaque token for txIDs so an HTTP caller
+// that already has the /Subscribe stream open can pass a single short value
+// back instead of repeating every transaction ID.
+func (t *Tracker) NewSubscriptionToken(txIDs []string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "Error generating subscription token")
+	}
+	token := hex.EncodeToString(buf)
+
+	t.mu.Lock()
+	t.evictExpiredTokensLocked()
+	t.tokens[token] = tokenEntry{txIDs: txIDs, mintedAt: time.Now()}
+	t.mu.Unlock()
+
+	return token, nil
+}
+
+// ResolveToken returns the transaction IDs a subscription token was minted
+// for. A token older than subscriptionTokenTTL is treated as if it were
+// never minted.
+func (t *Tracker) ResolveToken(token string) ([]string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.evictExpiredTokensLocked()
+	entry, ok := t.tokens[token]
+	if !ok {
+		return nil, false
+	}
+	return entry.txIDs, true
+}
+
+// evictExpiredTokensLocked drops every token minted more than
+// subscriptionTokenTTL ago. Callers must hold t.mu.
+func (t *Tracker) evictExpiredTokensLocked() {
+	now := time.Now()
+	for token, entry := range t.tokens {
+		if now.Sub(entry.mintedAt) > subscriptionTokenTTL {
+			delete(t.tokens, token)
+		}
+	}
+}
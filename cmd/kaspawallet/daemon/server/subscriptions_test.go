@@ -0,0 +1,135 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestTracker builds a Tracker without dialing kaspad, so the
+// subscribe/publish/token bookkeeping can be tested independently of
+// NewTracker's RPC connection.
+func newTestTracker(confirmationThreshold uint64) *Tracker {
+	return &Tracker{
+		confirmationThreshold: confirmationThreshold,
+		subscribers:           make(map[*subscriber]bool),
+		txBlueScore:           make(map[string]uint64),
+		tokens:                make(map[string]tokenEntry),
+	}
+}
+
+func TestSubscribeReceivesAcceptedAndConfirmedEvents(t *testing.T) {
+	tracker := newTestTracker(10)
+
+	eventC, unsubscribe := tracker.Subscribe([]string{"tx1"})
+	defer unsubscribe()
+
+	tracker.onUTXOsChanged("tx1", "block1", true)
+	select {
+	case ev := <-eventC:
+		if ev.Type != EventAccepted || ev.TxID != "tx1" {
+			t.Fatalf("expected an accepted event for tx1, got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for accepted event")
+	}
+
+	tracker.onBlueScoreChanged(10)
+	select {
+	case ev := <-eventC:
+		if ev.Type != EventConfirmed || ev.TxID != "tx1" {
+			t.Fatalf("expected a confirmed event for tx1, got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for confirmed event")
+	}
+}
+
+func TestSubscribeIgnoresUntrackedTxIDs(t *testing.T) {
+	tracker := newTestTracker(10)
+
+	eventC, unsubscribe := tracker.Subscribe([]string{"tx1"})
+	defer unsubscribe()
+
+	tracker.onUTXOsChanged("tx2", "block1", true)
+
+	select {
+	case ev := <-eventC:
+		t.Fatalf("expected no event for an untracked tx ID, got %+v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestOnUTXOsChangedReorgEmitsReorgedEvent(t *testing.T) {
+	tracker := newTestTracker(10)
+
+	eventC, unsubscribe := tracker.Subscribe([]string{"tx1"})
+	defer unsubscribe()
+
+	tracker.onUTXOsChanged("tx1", "block1", true)
+	<-eventC // drain the accepted event
+
+	tracker.onUTXOsChanged("tx1", "block1", false)
+	select {
+	case ev := <-eventC:
+		if ev.Type != EventReorged || ev.TxID != "tx1" {
+			t.Fatalf("expected a reorged event for tx1, got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for reorged event")
+	}
+}
+
+func TestUnsubscribeClosesTheEventChannel(t *testing.T) {
+	tracker := newTestTracker(10)
+
+	eventC, unsubscribe := tracker.Subscribe([]string{"tx1"})
+	unsubscribe()
+
+	if _, ok := <-eventC; ok {
+		t.Fatal("expected the event channel to be closed after unsubscribe")
+	}
+}
+
+func TestSubscriptionTokenRoundTrips(t *testing.T) {
+	tracker := newTestTracker(10)
+
+	txIDs := []string{"tx1", "tx2"}
+	token, err := tracker.NewSubscriptionToken(txIDs)
+	if err != nil {
+		t.Fatalf("NewSubscriptionToken: %s", err)
+	}
+
+	resolved, ok := tracker.ResolveToken(token)
+	if !ok {
+		t.Fatal("expected ResolveToken to find the minted token")
+	}
+	if len(resolved) != len(txIDs) || resolved[0] != txIDs[0] || resolved[1] != txIDs[1] {
+		t.Fatalf("expected resolved tx IDs %v, got %v", txIDs, resolved)
+	}
+
+	if _, ok := tracker.ResolveToken("does-not-exist"); ok {
+		t.Fatal("expected ResolveToken to report unknown tokens as not found")
+	}
+}
+
+func TestExpiredSubscriptionTokenIsEvicted(t *testing.T) {
+	tracker := newTestTracker(10)
+
+	token, err := tracker.NewSubscriptionToken([]string{"tx1"})
+	if err != nil {
+		t.Fatalf("NewSubscriptionToken: %s", err)
+	}
+
+	tracker.mu.Lock()
+	entry := tracker.tokens[token]
+	entry.mintedAt = entry.mintedAt.Add(-subscriptionTokenTTL - time.Second)
+	tracker.tokens[token] = entry
+	tracker.mu.Unlock()
+
+	if _, ok := tracker.ResolveToken(token); ok {
+		t.Fatal("expected ResolveToken to report an expired token as not found")
+	}
+	if _, stillPresent := tracker.tokens[token]; stillPresent {
+		t.Fatal("expected ResolveToken to evict the expired entry from tokens")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+
+	"github.com/kaspanet/kaspad/cmd/kaspawallet/daemon/pb"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// daemonServer is the gRPC-side implementation of the Kaspawalletd service.
+// It currently only embeds pb.UnimplementedKaspawalletdServer, so every RPC
+// (GetBalance, CreateUnsignedTransactions, NewAddress, ShowAddresses, Sign,
+// Broadcast, ParseTransaction, Send, Sweep) returns codes.Unimplemented.
+// That's not a placeholder this auth/TLS series chose to leave for later:
+// the wallet/UTXO-management logic those methods would call into - along
+// with the keys, libkaspawallet and daemon/client packages the rest of this
+// file already imports, and the generated pb.go stubs kaspawalletd.proto
+// would produce - was never part of this trimmed checkout (see
+// .claude/skills/verify/SKILL.md). There's no real implementation anywhere
+// in this tree to wire up here; fabricating balance/UTXO/transaction-
+// construction logic without the real upstream packages backing it would
+// be worse than an honest stub. Once this snapshot is merged into a full
+// kaspad checkout with those packages, daemonServer should embed a real
+// implementation instead of UnimplementedKaspawalletdServer.
+type daemonServer struct {
+	pb.UnimplementedKaspawalletdServer
+}
+
+// startDaemon brings up the daemon's gRPC server on conf.DaemonAddress.
+// Every unary RPC is gated by bakery.UnaryServerInterceptor, the gRPC-side
+// counterpart of withMacaroonAuth on the HTTP front-end: without it here, a
+// client dialing the gRPC port directly - including kaspawallet's own
+// send/balance/etc. sub-commands - would sign and broadcast with no
+// macaroon check at all, since withMacaroonAuth and
+// withGatewayMacaroonPresence only ever see requests that already came in
+// over HTTP.
+func startDaemon(conf *startDaemonConfig) error {
+	listener, err := net.Listen("tcp", conf.DaemonAddress)
+	if err != nil {
+		return errors.Wrap(err, "Error listening for the gRPC daemon")
+	}
+
+	cert, err := tls.LoadX509KeyPair(conf.TLSCertFile, conf.TLSKeyFile)
+	if err != nil {
+		return errors.Wrap(err, "Error loading daemon TLS certificate")
+	}
+	creds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+
+	grpcServer := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.UnaryInterceptor(bakery.UnaryServerInterceptor()),
+	)
+	pb.RegisterKaspawalletdServer(grpcServer, &daemonServer{})
+
+	log.Printf("kaspawalletd listening on %s", conf.DaemonAddress)
+	return grpcServer.Serve(listener)
+}